@@ -19,6 +19,8 @@
 package runtime
 
 import (
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/go-multierror"
@@ -29,6 +31,49 @@ import (
 	"github.com/onflow/cadence/runtime/sema"
 )
 
+// ComputationKind indicates the kind of computation that is being metered.
+//
+// This allows a host to price or rate-limit individual operations differently,
+// instead of treating all computation as fungible.
+type ComputationKind uint
+
+const (
+	ComputationKindUnknown ComputationKind = iota
+	ComputationKindHash
+	ComputationKindVerifySignature
+	ComputationKindAddAccountKey
+	ComputationKindRevokeAccountKey
+	ComputationKindValidatePublicKey
+	ComputationKindAllocateStorageIndex
+	ComputationKindCreateAccount
+	ComputationKindEmitEvent
+	ComputationKindGenerateUUID
+	ComputationKindGetAccountBalance
+	ComputationKindGetAccountAvailableBalance
+	ComputationKindGetStorageCapacity
+	ComputationKindGetStorageUsed
+	ComputationKindGetValue
+	ComputationKindSetValue
+	ComputationKindValueExists
+	ComputationKindGetAccountContractCode
+	ComputationKindUpdateAccountContractCode
+	ComputationKindRemoveAccountContractCode
+	ComputationKindGetAccountContractNames
+	ComputationKindGetBlockAtHeight
+	ComputationKindGetCurrentBlockHeight
+	ComputationKindResolveLocation
+	ComputationKindGetCode
+	ComputationKindGetOrLoadProgram
+	ComputationKindGetRandomSourceHistory
+
+	// Appended after the above, which had already shipped with fixed values:
+	// new kinds must always be appended at the end of this block, never spliced
+	// in, since hosts hang per-kind prices/weights off these values.
+	ComputationKindBLSVerifyPOP
+	ComputationKindBLSAggregateSignatures
+	ComputationKindBLSAggregatePublicKeys
+)
+
 const BlockHashLength = 32
 
 type BlockHash [BlockHashLength]byte
@@ -90,18 +135,54 @@ type AccountStorage interface {
 	// StoredKeys returns an iterator of all storage keys and their sizes owned by the given account.
 	StoredKeys(address Address) (iter StorageKeyIterator, err error)
 	// StorageUsed gets storage used in bytes by the address at the moment of the function call.
-	// NOTE: Storage capacity functionality is provided through injected functions.
 	StorageUsed(address Address) (value uint64, err error)
+	// StorageCapacity gets storage capacity in bytes available to the address at the moment of the function call.
+	StorageCapacity(address Address) (value uint64, err error)
+}
+
+// AccountBalances exposes the FLOW token balance of accounts.
+type AccountBalances interface {
+	// Balance gets the balance of an account in the smallest unit of FLOW.
+	Balance(address Address) (value uint64, err error)
+	// AvailableBalance gets the balance of an account that is available to be moved,
+	// i.e. the balance minus the amount required to cover the account's storage.
+	AvailableBalance(address Address) (value uint64, err error)
 }
 
-// AccountKeys manages account keys
+// AccountKey represents a public key associated with an account.
+type AccountKey struct {
+	Index     int
+	PublicKey []byte
+	SignAlgo  string
+	HashAlgo  string
+	Weight    uint64
+	IsRevoked bool
+}
+
+// AccountKeys manages account keys.
+//
+// The structured, AccountKey-typed methods are named AddStructuredAccountKey/
+// RevokeStructuredAccountKey rather than overloading AddAccountKey/RevokeAccountKey,
+// to keep the pre-existing []byte-based methods source-compatible for existing
+// implementers.
 type AccountKeys interface {
 	// AddAccountKey appends a key to an account.
 	AddAccountKey(address Address, publicKey []byte) error
-	// RemoveAccountKey removes a key from an account by index.
+	// RevokeAccountKey removes a key from an account by index.
 	RevokeAccountKey(address Address, index int) (publicKey []byte, err error)
 	// AccountPublicKey returns the account key for the given index.
 	AccountPublicKey(address Address, index int) (publicKey []byte, err error)
+
+	// AddStructuredAccountKey appends a structured key to an account and returns the stored key.
+	AddStructuredAccountKey(address Address, key AccountKey) (AccountKey, error)
+	// RevokeStructuredAccountKey revokes a structured key from an account by index
+	// and returns the revoked key.
+	RevokeStructuredAccountKey(address Address, index int) (AccountKey, error)
+	// GetAccountKey returns the structured account key for the given index,
+	// and false if no key exists at that index.
+	GetAccountKey(address Address, index int) (key AccountKey, exists bool, err error)
+	// AccountKeysCount returns the number of keys on an account.
+	AccountKeysCount(address Address) (count uint64, err error)
 }
 
 // LocationResolver provides functionality to locate codes
@@ -146,7 +227,14 @@ type Results interface {
 	// ErrorCount returns number of errors in the error collection.
 	ErrorCount() uint
 
+	// MeterComputation meters the use of computation of the given kind, at the given intensity,
+	// increasing the computation usage accumulator by an amount the host derives from both.
+	// This allows hosts to price or rate-limit individual kinds of computation differently.
+	MeterComputation(kind ComputationKind, intensity uint64) error
 	// AddComputationUsed increases the computation usage accumulator by the given amount.
+	//
+	// Deprecated: this is a legacy fallback for hosts that do not distinguish between
+	// kinds of computation. New call sites should use MeterComputation instead.
 	AddComputationUsed(uint64) error
 	// ComputationSpent returns the total amount of computation spent during the execution.
 	ComputationSpent() uint64
@@ -156,14 +244,25 @@ type Results interface {
 }
 
 // ProgramCache provides caching functionality for Cadence programs (ASTs).
-//
 type ProgramCache interface {
 	// GetCachedProgram attempts to get a parsed program from a cache.
+	//
+	// Deprecated: this is a legacy fallback, retained for backwards compatibility.
+	// Combined with CacheProgram it forms a check-then-act pair, so concurrent callers
+	// importing the same location may each parse it before racing to store the result.
+	// New call sites should use GetOrLoadProgram instead.
 	GetCachedProgram(Location) (*ast.Program, error)
 	// CacheProgram adds a parsed program to a cache.
+	//
+	// Deprecated: see GetCachedProgram.
 	CacheProgram(Location, *ast.Program) error
+	// GetOrLoadProgram returns the cached program for the given location, or, on a cache miss,
+	// serializes concurrent callers for that location, invokes load exactly once, caches the
+	// result, and returns it to all waiters.
+	GetOrLoadProgram(location Location, load func() (*ast.Program, error)) (*ast.Program, error)
 }
 
+// CryptoProvider gives the interpreter access to cryptographic primitives.
 type CryptoProvider interface {
 	// VerifySignature returns true if the given signature was produced by signing the given tag + data
 	// using the given public key, signature algorithm, and hash algorithm.
@@ -177,6 +276,15 @@ type CryptoProvider interface {
 	) (bool, error)
 	// Hash returns the digest of hashing the given data with using the given hash algorithm.
 	Hash(data []byte, hashAlgorithm string) ([]byte, error)
+	// ValidatePublicKey returns true if the given public key is valid for the given signature algorithm,
+	// allowing callers such as AccountKeys.AddStructuredAccountKey to reject malformed keys early.
+	ValidatePublicKey(publicKey []byte, signatureAlgorithm string) (bool, error)
+	// BLSVerifyPOP verifies a BLS proof of possession for the given public key.
+	BLSVerifyPOP(publicKey []byte, proof []byte) (bool, error)
+	// BLSAggregateSignatures aggregates the given BLS signatures into a single signature.
+	BLSAggregateSignatures(signatures [][]byte) ([]byte, error)
+	// BLSAggregatePublicKeys aggregates the given BLS public keys into a single public key.
+	BLSAggregatePublicKeys(publicKeys [][]byte, signatureAlgorithm string) ([]byte, error)
 }
 
 type Metrics interface {
@@ -211,6 +319,29 @@ type Utils interface {
 	GenerateUUID() (uint64, error)
 }
 
+// BlockInfo provides access to the chain's consensus-derived block metadata,
+// so a RandomSource can attribute a random seed to the block it was sealed in.
+type BlockInfo interface {
+	// GetCurrentBlockHeight returns the height of the current block.
+	GetCurrentBlockHeight() (uint64, error)
+	// GetBlockAtHeight returns the block at the given height.
+	GetBlockAtHeight(height uint64) (block Block, exists bool, err error)
+}
+
+// RandomSource provides access to the chain's committed random beacon history,
+// from which Cadence derives per-transaction pseudo-random values.
+//
+// The seed returned for a given block height must be unpredictable at the time
+// transactions for that block are sealed, but publicly recoverable afterwards,
+// so that contract logic built on top of it remains auditable.
+type RandomSource interface {
+	BlockInfo
+
+	// RandomSourceHistory returns the committed random seed for the given,
+	// already-sealed block height.
+	RandomSourceHistory(blockHeight uint64) ([]byte, error)
+}
+
 type EmptyAccounts struct{}
 
 var _ Accounts = &EmptyAccounts{}
@@ -287,6 +418,18 @@ func (i *EmptyAccountStorage) StoredKeys(_ Address) (StorageKeyIterator, error)
 	return nil, nil
 }
 
+type EmptyAccountBalances struct{}
+
+var _ AccountBalances = &EmptyAccountBalances{}
+
+func (i *EmptyAccountBalances) Balance(_ Address) (uint64, error) {
+	return 0, nil
+}
+
+func (i *EmptyAccountBalances) AvailableBalance(_ Address) (uint64, error) {
+	return 0, nil
+}
+
 type EmptyAccountKeys struct{}
 
 var _ AccountKeys = &EmptyAccountKeys{}
@@ -303,6 +446,22 @@ func (i *EmptyAccountKeys) AccountPublicKey(_ Address, _ int) ([]byte, error) {
 	return nil, nil
 }
 
+func (i *EmptyAccountKeys) AddStructuredAccountKey(_ Address, key AccountKey) (AccountKey, error) {
+	return key, nil
+}
+
+func (i *EmptyAccountKeys) RevokeStructuredAccountKey(_ Address, _ int) (AccountKey, error) {
+	return AccountKey{}, nil
+}
+
+func (i *EmptyAccountKeys) GetAccountKey(_ Address, _ int) (AccountKey, bool, error) {
+	return AccountKey{}, false, nil
+}
+
+func (i *EmptyAccountKeys) AccountKeysCount(_ Address) (uint64, error) {
+	return 0, nil
+}
+
 type EmptyCryptoProvider struct{}
 
 var _ CryptoProvider = &EmptyCryptoProvider{}
@@ -325,6 +484,33 @@ func (i *EmptyCryptoProvider) Hash(
 	return nil, nil
 }
 
+func (i *EmptyCryptoProvider) ValidatePublicKey(
+	_ []byte,
+	_ string,
+) (bool, error) {
+	return false, nil
+}
+
+func (i *EmptyCryptoProvider) BLSVerifyPOP(
+	_ []byte,
+	_ []byte,
+) (bool, error) {
+	return false, nil
+}
+
+func (i *EmptyCryptoProvider) BLSAggregateSignatures(
+	_ [][]byte,
+) ([]byte, error) {
+	return nil, nil
+}
+
+func (i *EmptyCryptoProvider) BLSAggregatePublicKeys(
+	_ [][]byte,
+	_ string,
+) ([]byte, error) {
+	return nil, nil
+}
+
 type EmptyProgramCache struct{}
 
 var _ ProgramCache = &EmptyProgramCache{}
@@ -337,6 +523,89 @@ func (i *EmptyProgramCache) CacheProgram(_ Location, _ *ast.Program) error {
 	return nil
 }
 
+func (i *EmptyProgramCache) GetOrLoadProgram(
+	_ Location,
+	load func() (*ast.Program, error),
+) (*ast.Program, error) {
+	return load()
+}
+
+// SyncMapProgramCache is a ProgramCache demonstrating singleflight semantics
+// for GetOrLoadProgram: concurrent callers for the same location block on a
+// shared in-flight load instead of each parsing independently. The cache-hit
+// check, in-flight-call registration, and completion cleanup are all performed
+// under a single mutex, so a caller can never observe a gap between a leader's
+// call finishing and its result becoming visible: it either joins the in-flight
+// call or sees the cached result, never a false miss that triggers a second load.
+type SyncMapProgramCache struct {
+	mu       sync.Mutex
+	programs map[Location]*ast.Program
+	inFlight map[Location]*syncMapProgramCacheCall
+}
+
+var _ ProgramCache = &SyncMapProgramCache{}
+
+type syncMapProgramCacheCall struct {
+	done    chan struct{}
+	program *ast.Program
+	err     error
+}
+
+func (c *SyncMapProgramCache) GetCachedProgram(location Location) (*ast.Program, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.programs[location], nil
+}
+
+func (c *SyncMapProgramCache) CacheProgram(location Location, program *ast.Program) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.programs == nil {
+		c.programs = make(map[Location]*ast.Program)
+	}
+	c.programs[location] = program
+	return nil
+}
+
+func (c *SyncMapProgramCache) GetOrLoadProgram(
+	location Location,
+	load func() (*ast.Program, error),
+) (*ast.Program, error) {
+	c.mu.Lock()
+	if program, ok := c.programs[location]; ok {
+		c.mu.Unlock()
+		return program, nil
+	}
+	if call, ok := c.inFlight[location]; ok {
+		c.mu.Unlock()
+		<-call.done
+		return call.program, call.err
+	}
+
+	call := &syncMapProgramCacheCall{done: make(chan struct{})}
+	if c.inFlight == nil {
+		c.inFlight = make(map[Location]*syncMapProgramCacheCall)
+	}
+	c.inFlight[location] = call
+	c.mu.Unlock()
+
+	call.program, call.err = load()
+
+	c.mu.Lock()
+	if call.err == nil {
+		if c.programs == nil {
+			c.programs = make(map[Location]*ast.Program)
+		}
+		c.programs[location] = call.program
+	}
+	delete(c.inFlight, location)
+	c.mu.Unlock()
+
+	close(call.done)
+
+	return call.program, call.err
+}
+
 type EmptyResults struct{}
 
 var _ Results = &EmptyResults{}
@@ -389,10 +658,14 @@ func (i *EmptyResults) ErrorCount() uint {
 	return 0
 }
 
-func (i *EmptyResults) AddComputationUsed(_ uint64) error {
+func (i *EmptyResults) MeterComputation(_ ComputationKind, _ uint64) error {
 	return nil
 }
 
+func (i *EmptyResults) AddComputationUsed(intensity uint64) error {
+	return i.MeterComputation(ComputationKindUnknown, intensity)
+}
+
 func (i *EmptyResults) ComputationSpent() uint64 {
 	return 0
 }
@@ -409,17 +682,23 @@ func (i *EmptyUtils) GenerateUUID() (uint64, error) {
 	return 0, nil
 }
 
-// func (i *EmptyRuntimeInterface) GetCurrentBlockHeight() (uint64, error) {
-// 	return 0, nil
-// }
+// EmptyRandomSource is a RandomSource that has no random beacon history available,
+// for hosts that do not support on-chain randomness.
+type EmptyRandomSource struct{}
 
-// func (i *EmptyRuntimeInterface) GetBlockAtHeight(_ uint64) (block Block, exists bool, err error) {
-// 	return
-// }
+var _ RandomSource = &EmptyRandomSource{}
 
-// func (i *EmptyRuntimeInterface) UnsafeRandom() (uint64, error) {
-// 	return 0, nil
-// }
+func (i *EmptyRandomSource) GetCurrentBlockHeight() (uint64, error) {
+	return 0, nil
+}
+
+func (i *EmptyRandomSource) GetBlockAtHeight(_ uint64) (block Block, exists bool, err error) {
+	return
+}
+
+func (i *EmptyRandomSource) RandomSourceHistory(_ uint64) ([]byte, error) {
+	return nil, fmt.Errorf("RandomSourceHistory is not supported")
+}
 
 // func (i *EmptyAccounts) ResolveLocation(identifiers []Identifier, location Location) ([]ResolvedLocation, error) {
 // 	return []ResolvedLocation{
@@ -0,0 +1,148 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2019-2020 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+)
+
+func TestEmptyResultsAddComputationUsedAndMeterComputationReturnNil(t *testing.T) {
+	results := &EmptyResults{}
+
+	if err := results.AddComputationUsed(42); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := results.MeterComputation(ComputationKindUnknown, 42); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestEmptyAccountStorageAndBalancesAreZeroValue(t *testing.T) {
+	storage := &EmptyAccountStorage{}
+	if capacity, err := storage.StorageCapacity(Address{}); err != nil || capacity != 0 {
+		t.Fatalf("expected (0, nil), got (%d, %v)", capacity, err)
+	}
+
+	balances := &EmptyAccountBalances{}
+	if balance, err := balances.Balance(Address{}); err != nil || balance != 0 {
+		t.Fatalf("expected (0, nil), got (%d, %v)", balance, err)
+	}
+	if available, err := balances.AvailableBalance(Address{}); err != nil || available != 0 {
+		t.Fatalf("expected (0, nil), got (%d, %v)", available, err)
+	}
+}
+
+func TestAccountKeysKeepsLegacyAndStructuredMethodsSideBySide(t *testing.T) {
+	keys := &EmptyAccountKeys{}
+
+	if err := keys.AddAccountKey(Address{}, []byte{1, 2, 3}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	structuredKey := AccountKey{PublicKey: []byte{1, 2, 3}, Weight: 1000}
+	added, err := keys.AddStructuredAccountKey(Address{}, structuredKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if added.Weight != structuredKey.Weight || string(added.PublicKey) != string(structuredKey.PublicKey) {
+		t.Errorf("expected %+v, got %+v", structuredKey, added)
+	}
+}
+
+// TestSyncMapProgramCacheLoadsConcurrentCallersOnce exercises the window that
+// a two-sync.Map implementation gets wrong: followers arrive staggered, some
+// only after the leader has already entered load but before it has published
+// a result. The leader's load blocks on releaseLoad until every follower has
+// had a chance to reach GetOrLoadProgram, so a follower that incorrectly missed
+// both the cache and the in-flight registration would start a second, concurrent
+// load instead of joining the first.
+func TestSyncMapProgramCacheLoadsConcurrentCallersOnce(t *testing.T) {
+	cache := &SyncMapProgramCache{}
+	location := common.StringLocation("test")
+
+	var loadCount int32
+	loadStarted := make(chan struct{})
+	releaseLoad := make(chan struct{})
+
+	const callers = 50
+	programs := make([]*ast.Program, callers)
+	errs := make([]error, callers)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		programs[0], errs[0] = cache.GetOrLoadProgram(location, func() (*ast.Program, error) {
+			atomic.AddInt32(&loadCount, 1)
+			close(loadStarted)
+			<-releaseLoad
+			return &ast.Program{}, nil
+		})
+	}()
+
+	<-loadStarted
+
+	for i := 1; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			programs[i], errs[i] = cache.GetOrLoadProgram(location, func() (*ast.Program, error) {
+				atomic.AddInt32(&loadCount, 1)
+				return &ast.Program{}, nil
+			})
+		}(i)
+	}
+
+	// Give the staggered followers a chance to reach GetOrLoadProgram and
+	// join the in-flight call before the leader's load completes.
+	time.Sleep(10 * time.Millisecond)
+	close(releaseLoad)
+
+	wg.Wait()
+
+	for i := 0; i < callers; i++ {
+		if errs[i] != nil {
+			t.Fatalf("caller %d: unexpected error: %v", i, errs[i])
+		}
+		if programs[i] != programs[0] {
+			t.Errorf("caller %d: got a different *ast.Program than caller 0", i)
+		}
+	}
+
+	if got := atomic.LoadInt32(&loadCount); got != 1 {
+		t.Errorf("expected load to run exactly once, ran %d times", got)
+	}
+}
+
+func TestEmptyRandomSourceHistoryIsUnsupported(t *testing.T) {
+	source := &EmptyRandomSource{}
+
+	if _, err := source.RandomSourceHistory(1); err == nil {
+		t.Error("expected an error, got nil")
+	}
+	if height, err := source.GetCurrentBlockHeight(); err != nil || height != 0 {
+		t.Fatalf("expected (0, nil), got (%d, %v)", height, err)
+	}
+}